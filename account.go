@@ -0,0 +1,26 @@
+package lastpass
+
+// Account represents a LastPass "site" entry: a set of credentials plus the
+// metadata LastPass attaches to it (group, notes, sharing, timestamps).
+//
+// Fields map directly onto the decrypted "account" chunks found in the
+// accounts blob returned by the LastPass vault endpoints.
+type Account struct {
+	ID       string
+	Name     string
+	Username string
+	Password string
+	URL      string
+	Group    string
+	// Share is the name of the shared folder the account belongs to, or
+	// empty if the account is private to the logged in user.
+	Share string
+	Notes string
+
+	// LastModifiedGMT is the unix timestamp (as decimal string) of the last
+	// server-side modification, in GMT.
+	LastModifiedGMT string
+	// LastTouch is the unix timestamp (as decimal string) the account was
+	// last accessed, in the account owner's local timezone.
+	LastTouch string
+}
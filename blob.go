@@ -0,0 +1,169 @@
+package lastpass
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// chunk is a single TLV record from the LastPass accounts blob: a 4-byte
+// ASCII tag, a 4-byte big-endian length, and the payload.
+type chunk struct {
+	tag     string
+	payload []byte
+}
+
+// readChunks walks the blob returned by login_check.php / the vault
+// endpoints and returns all of its chunks in order.
+func readChunks(r io.Reader) ([]chunk, error) {
+	br := bufio.NewReader(r)
+	var chunks []chunk
+	for {
+		tag := make([]byte, 4)
+		if _, err := io.ReadFull(br, tag); err != nil {
+			if err == io.EOF {
+				return chunks, nil
+			}
+			return nil, fmt.Errorf("lastpass: reading chunk tag: %w", err)
+		}
+
+		var length uint32
+		if err := binary.Read(br, binary.BigEndian, &length); err != nil {
+			return nil, fmt.Errorf("lastpass: reading chunk length: %w", err)
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return nil, fmt.Errorf("lastpass: reading chunk payload: %w", err)
+		}
+
+		chunks = append(chunks, chunk{tag: string(tag), payload: payload})
+	}
+}
+
+// parseAccountChunk decodes an "ACCT" chunk's payload into an Account,
+// decrypting the fields that LastPass encrypts per-account. accountsVersion
+// is Capabilities().AccountsVersion, which selects the field layout: older
+// accounts predate the "vulnerable" field LastPass added to flag
+// known-compromised passwords.
+func parseAccountChunk(payload []byte, encryptionKey []byte, accountsVersion string) (*Account, error) {
+	fields, err := readChunkFields(payload, accountChunkFieldNames(accountsVersion))
+	if err != nil {
+		return nil, fmt.Errorf("lastpass: parsing account chunk: %w", err)
+	}
+
+	name, err := decryptField(fields["name"], encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	username, err := decryptField(fields["username"], encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	password, err := decryptField(fields["password"], encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := decryptField(fields["notes"], encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Account{
+		ID:              string(fields["id"]),
+		Name:            name,
+		Username:        username,
+		Password:        password,
+		URL:             decodeHexURL(fields["url"]),
+		Group:           string(fields["group"]),
+		Share:           string(fields["share"]),
+		Notes:           notes,
+		LastModifiedGMT: string(fields["last_modified_gmt"]),
+		LastTouch:       string(fields["last_touch"]),
+	}, nil
+}
+
+// legacyAccountChunkFieldNames is the field layout accounts predating
+// accts_version "200" use: LastPass had not yet added "vulnerable" (a flag
+// for passwords caught up in a known breach) to the per-account record.
+var legacyAccountChunkFieldNames = []string{
+	"id", "name", "group", "url", "notes", "fav", "sharedfromaid",
+	"username", "password", "password_protect", "gen_pw", "sn",
+	"last_touch", "autologin", "never_autofill", "realm_data",
+	"fiid", "custom_js", "submit_id", "captcha_id", "urid",
+	"basic_auth", "method", "action", "groupid", "deleted",
+	"attachkey", "attachpresent", "individualshare", "notetype",
+	"noalert", "last_modified_gmt", "hasbeenshared", "last_pwchange_gmt",
+	"created_gmt",
+}
+
+// currentAccountChunkFieldNames adds "vulnerable" after "created_gmt".
+var currentAccountChunkFieldNames = append(append([]string{}, legacyAccountChunkFieldNames...), "vulnerable")
+
+// accountChunkFieldNames returns the field layout for accountsVersion, i.e.
+// Capabilities().AccountsVersion. Versions 200 and above use the current
+// layout; anything else, including an unreported (empty) or unparseable
+// version, defaults to the legacy layout, since assuming the newer one
+// would misattribute every field after the divergence point.
+func accountChunkFieldNames(accountsVersion string) []string {
+	if version, err := strconv.Atoi(accountsVersion); err == nil && version >= 200 {
+		return currentAccountChunkFieldNames
+	}
+	return legacyAccountChunkFieldNames
+}
+
+// readChunkFields splits an account chunk payload into its named
+// sub-fields, each of which is itself length-prefixed, per names.
+func readChunkFields(payload []byte, names []string) (map[string][]byte, error) {
+	fields := make(map[string][]byte, len(names))
+	offset := 0
+	for _, name := range names {
+		if offset+4 > len(payload) {
+			break
+		}
+		length := int(binary.BigEndian.Uint32(payload[offset : offset+4]))
+		offset += 4
+		if offset+length > len(payload) {
+			return nil, fmt.Errorf("field %q truncated", name)
+		}
+		fields[name] = payload[offset : offset+length]
+		offset += length
+	}
+	return fields, nil
+}
+
+// decryptField decrypts a field that is stored as a base64 string; empty
+// fields pass through untouched.
+func decryptField(raw []byte, encryptionKey []byte) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	data, err := base64.StdEncoding.DecodeString(string(raw))
+	if err != nil {
+		// Some fields (e.g. legacy ECB-encrypted ones) are not valid
+		// base64-CBC; fall back to the raw value rather than failing the
+		// whole parse.
+		return string(raw), nil
+	}
+	plaintext, err := decryptAES256CBC(encryptionKey, data)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func decodeHexURL(raw []byte) string {
+	decoded := make([]byte, len(raw)/2)
+	for i := range decoded {
+		var b byte
+		_, err := fmt.Sscanf(string(raw[i*2:i*2+2]), "%02x", &b)
+		if err != nil {
+			return string(raw)
+		}
+		decoded[i] = b
+	}
+	return string(decoded)
+}
@@ -0,0 +1,83 @@
+package lastpass
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// encodeChunkField writes a single length-prefixed sub-field, matching the
+// layout readChunkFields expects.
+func encodeChunkField(buf *bytes.Buffer, value string) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(value)))
+	buf.Write(length[:])
+	buf.WriteString(value)
+}
+
+// buildAccountChunkPayload lays out values in names order, leaving any name
+// not present in values empty, the way a real ACCT chunk does for fields the
+// account doesn't use.
+func buildAccountChunkPayload(names []string, values map[string]string) []byte {
+	var buf bytes.Buffer
+	for _, name := range names {
+		encodeChunkField(&buf, values[name])
+	}
+	return buf.Bytes()
+}
+
+func TestAccountChunkFieldNames_SelectsLayoutByAccountsVersion(t *testing.T) {
+	tests := []struct {
+		accountsVersion string
+		wantVulnerable  bool
+	}{
+		{"", false},
+		{"100", false},
+		{"199", false},
+		{"200", true},
+		{"301", true},
+		{"not-a-number", false},
+	}
+	for _, tt := range tests {
+		names := accountChunkFieldNames(tt.accountsVersion)
+		_, hasVulnerable := indexOf(names, "vulnerable")
+		if hasVulnerable != tt.wantVulnerable {
+			t.Errorf("accountsVersion %q: has \"vulnerable\" field = %v, want %v", tt.accountsVersion, hasVulnerable, tt.wantVulnerable)
+		}
+	}
+}
+
+func TestParseAccountChunk_UsesVersionAppropriateFieldLayout(t *testing.T) {
+	values := map[string]string{
+		"id":                "1",
+		"last_modified_gmt": "2020-01-01 00:00:00",
+		"vulnerable":        "1",
+	}
+
+	legacyPayload := buildAccountChunkPayload(legacyAccountChunkFieldNames, values)
+	acct, err := parseAccountChunk(legacyPayload, nil, "100")
+	if err != nil {
+		t.Fatalf("parseAccountChunk (legacy): %v", err)
+	}
+	if acct.LastModifiedGMT != "2020-01-01 00:00:00" {
+		t.Errorf("LastModifiedGMT = %q, want %q", acct.LastModifiedGMT, "2020-01-01 00:00:00")
+	}
+
+	currentPayload := buildAccountChunkPayload(currentAccountChunkFieldNames, values)
+	fields, err := readChunkFields(currentPayload, accountChunkFieldNames("301"))
+	if err != nil {
+		t.Fatalf("readChunkFields (current): %v", err)
+	}
+	if got := string(fields["vulnerable"]); got != "1" {
+		t.Errorf("vulnerable field = %q, want %q (only present in the current layout)", got, "1")
+	}
+}
+
+func indexOf(names []string, target string) (int, bool) {
+	for i, n := range names {
+		if n == target {
+			return i, true
+		}
+	}
+	return -1, false
+}
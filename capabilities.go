@@ -0,0 +1,86 @@
+package lastpass
+
+import "sync"
+
+// CapabilityKind identifies a single server-advertised feature that
+// internal codepaths may need to gate on, via Capabilities.Supports.
+type CapabilityKind int
+
+const (
+	// CapOutOfBandMultifactor indicates the account can complete
+	// multifactor authentication out of band (e.g. approving a push
+	// notification) instead of typing a one-time code.
+	CapOutOfBandMultifactor CapabilityKind = iota
+)
+
+// Capabilities describes what a particular LastPass account and server
+// version support, as advertised by the login_check.php / login.php
+// responses. Fetch it with Client.Capabilities.
+type Capabilities struct {
+	// AccountsVersion is the accts_version the server reported, used to
+	// pick a compatible accounts blob parser.
+	AccountsVersion string
+	// PBKDF2Iterations is the round count the server requires for the
+	// master password key derivation.
+	PBKDF2Iterations int
+	// MFAProviders lists the multifactor methods the account has
+	// enrolled, e.g. "googleauth", "yubikey", "outofband".
+	MFAProviders []string
+}
+
+// Supports reports whether kind applies to these Capabilities.
+func (c Capabilities) Supports(kind CapabilityKind) bool {
+	switch kind {
+	case CapOutOfBandMultifactor:
+		for _, p := range c.MFAProviders {
+			if p == "outofband" {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// capabilitiesState guards Client.capabilities and lets tests freeze it so
+// the login and accounts-parsing codepaths that gate on Capabilities don't
+// need a real server round trip.
+type capabilitiesState struct {
+	mu     sync.RWMutex
+	caps   Capabilities
+	frozen bool
+}
+
+func (s *capabilitiesState) get() Capabilities {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.caps
+}
+
+// set refreshes the cached Capabilities unless they have been frozen by
+// WithCapabilities, e.g. for tests that should not depend on live server
+// responses.
+func (s *capabilitiesState) set(caps Capabilities) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.frozen {
+		return
+	}
+	s.caps = caps
+}
+
+func (s *capabilitiesState) freeze(caps Capabilities) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.caps = caps
+	s.frozen = true
+}
+
+// Capabilities returns the server/account capabilities discovered at
+// login, or overridden via WithCapabilities. It does not make a network
+// call; capabilities are refreshed internally whenever login_check.php is
+// consulted, e.g. during login and the OfflineQueue's online() probe.
+func (c *Client) Capabilities() Capabilities {
+	return c.capabilities.get()
+}
@@ -0,0 +1,24 @@
+package lastpass
+
+import "testing"
+
+func TestCapabilities_Supports(t *testing.T) {
+	caps := Capabilities{
+		MFAProviders: []string{"googleauth", "outofband"},
+	}
+
+	if !caps.Supports(CapOutOfBandMultifactor) {
+		t.Error("expected CapOutOfBandMultifactor to be supported")
+	}
+}
+
+func TestWithCapabilities_FreezesAgainstOverwrite(t *testing.T) {
+	c := &Client{}
+	WithCapabilities(Capabilities{PBKDF2Iterations: 5000})(c)
+
+	c.capabilities.set(Capabilities{PBKDF2Iterations: 100100})
+
+	if got := c.Capabilities().PBKDF2Iterations; got != 5000 {
+		t.Fatalf("frozen capabilities were overwritten: got %d, want 5000", got)
+	}
+}
@@ -0,0 +1,535 @@
+// Package lastpass implements a client for the (undocumented) LastPass
+// vault API used by the official browser extensions and CLI.
+package lastpass
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultBaseURL = "https://lastpass.com"
+
+// Client is a LastPass session. Construct one with NewClient or
+// NewClientFromSession.
+type Client struct {
+	httpClient HTTPClient
+	baseURL    string
+
+	session *Session
+
+	// queue buffers mutations while the network is unreachable. It is nil
+	// unless the client was built with a queue (see OfflineQueue).
+	queue *OfflineQueue
+
+	// sharesMu guards sharesByName/sharesByID, the cache Shares() fills in
+	// and Add/Update/Delete consult to pre-validate writes.
+	sharesMu     sync.RWMutex
+	sharesByName map[string]*Share
+	sharesByID   map[string]*Share
+
+	// capabilities caches what the server/account advertised at login; see
+	// Capabilities and WithCapabilities.
+	capabilities capabilitiesState
+
+	// retryPolicy is nil unless the Client was built with WithRetryPolicy,
+	// in which case do{Idempotent,Mutation} retry through it.
+	retryPolicy *RetryPolicy
+}
+
+// NewClient logs in to LastPass with the given master username and
+// password and returns a ready-to-use Client.
+func NewClient(ctx context.Context, username, password string, opts ...ClientOption) (*Client, error) {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		baseURL:    defaultBaseURL,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	session, err := c.login(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+	c.session = session
+
+	return c, nil
+}
+
+// NewClientFromSession builds a Client from a previously obtained Session,
+// skipping the login round trip. This is how an OfflineQueue-backed client
+// is typically resumed after the process restarts.
+func NewClientFromSession(ctx context.Context, session *Session, opts ...ClientOption) (*Client, error) {
+	if session == nil {
+		return nil, fmt.Errorf("lastpass: session must not be nil")
+	}
+	c := &Client{
+		httpClient: http.DefaultClient,
+		baseURL:    defaultBaseURL,
+		session:    session,
+	}
+	// Carry AccountsVersion forward so a resumed client decodes the offline
+	// accounts snapshot with the right blob layout even before its first
+	// online round trip refreshes Capabilities(). WithCapabilities, if
+	// passed, still takes precedence since it runs after and freezes.
+	c.capabilities.set(Capabilities{AccountsVersion: session.AccountsVersion})
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Session returns the client's current session, which can be persisted and
+// later passed to NewClientFromSession to avoid a fresh login.
+func (c *Client) Session() (*Session, error) {
+	if c.session == nil {
+		return nil, fmt.Errorf("lastpass: client is not logged in")
+	}
+	return c.session, nil
+}
+
+// Accounts returns all accounts visible to the logged in user, including
+// those in shared folders. If the Client was built with an OfflineQueue and
+// the network is unreachable, Accounts falls back to the queue's last
+// accounts snapshot instead of failing.
+func (c *Client) Accounts(ctx context.Context) ([]*Account, error) {
+	if c.queue != nil && !c.online(ctx) {
+		blob, err := c.queue.store.LoadAccountsSnapshot()
+		if err != nil {
+			return nil, fmt.Errorf("lastpass: loading offline accounts snapshot: %w", err)
+		}
+		return c.ParseEncryptedAccounts(bytes.NewReader(blob))
+	}
+
+	blob, err := c.FetchEncryptedAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c.queue != nil {
+		if err := c.queue.store.SaveAccountsSnapshot(blob); err != nil {
+			return nil, fmt.Errorf("lastpass: saving offline accounts snapshot: %w", err)
+		}
+	}
+	return c.ParseEncryptedAccounts(bytes.NewReader(blob))
+}
+
+// FetchEncryptedAccounts downloads the raw, still-encrypted accounts blob.
+// Pair it with ParseEncryptedAccounts to decrypt it, e.g. to build an
+// offline accounts snapshot.
+func (c *Client) FetchEncryptedAccounts(ctx context.Context) ([]byte, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/getaccts.php", url.Values{
+		"mobile": {"1"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doIdempotent(req)
+	if err != nil {
+		return nil, fmt.Errorf("lastpass: fetching accounts: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("lastpass: reading accounts response: %w", err)
+	}
+	return body, nil
+}
+
+// ParseEncryptedAccounts decrypts a blob previously obtained via
+// FetchEncryptedAccounts (or cached to disk) into Accounts, without making
+// any network call. This is the primitive an offline accounts snapshot is
+// built on.
+func (c *Client) ParseEncryptedAccounts(r io.Reader) ([]*Account, error) {
+	if c.session == nil || c.session.EncryptionKey == nil {
+		return nil, fmt.Errorf("lastpass: client has no encryption key")
+	}
+
+	chunks, err := readChunks(r)
+	if err != nil {
+		return nil, err
+	}
+
+	accountsVersion := c.Capabilities().AccountsVersion
+
+	var accounts []*Account
+	for _, ch := range chunks {
+		if ch.tag != "ACCT" {
+			continue
+		}
+		acct, err := parseAccountChunk(ch.payload, c.session.EncryptionKey, accountsVersion)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, acct)
+	}
+	return accounts, nil
+}
+
+// Add creates a new account. On success acct.ID is populated with the
+// server-assigned ID, except when the Add is buffered by an OfflineQueue:
+// acct.ID then stays empty, and replaying it later does not update acct.
+func (c *Client) Add(ctx context.Context, acct *Account) error {
+	if err := c.validateShareWrite(acct); err != nil {
+		return err
+	}
+	if c.queue != nil && !c.online(ctx) {
+		return c.queue.enqueue(ctx, opAdd, acct)
+	}
+	return c.upsert(ctx, acct, true)
+}
+
+// Update modifies an existing account. It returns an *AccountNotFoundError
+// if acct.ID does not exist.
+func (c *Client) Update(ctx context.Context, acct *Account) error {
+	if acct.ID == "" {
+		return &AccountNotFoundError{ID: acct.ID}
+	}
+	if err := c.validateShareWrite(acct); err != nil {
+		return err
+	}
+	if c.queue != nil && !c.online(ctx) {
+		return c.queue.enqueue(ctx, opUpdate, acct)
+	}
+	return c.upsert(ctx, acct, false)
+}
+
+// Delete removes an account. It returns an *AccountNotFoundError if
+// acct.ID does not exist.
+func (c *Client) Delete(ctx context.Context, acct *Account) error {
+	if acct.ID == "" {
+		return &AccountNotFoundError{ID: acct.ID}
+	}
+	// A delete sends no password, so only the share's read-only flag is
+	// checked, not HidePasswords.
+	if err := c.validateShareWrite(&Account{Share: acct.Share}); err != nil {
+		return err
+	}
+	if c.queue != nil && !c.online(ctx) {
+		return c.queue.enqueue(ctx, opDelete, acct)
+	}
+	return c.delete(ctx, acct)
+}
+
+// Logout ends the server-side session. The Client must not be used
+// afterwards.
+func (c *Client) Logout(ctx context.Context) error {
+	req, err := c.newRequest(ctx, http.MethodPost, "/logout.php", url.Values{
+		"method": {"cli"},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := c.doIdempotent(req)
+	if err != nil {
+		return fmt.Errorf("lastpass: logging out: %w", err)
+	}
+	return resp.Body.Close()
+}
+
+func (c *Client) upsert(ctx context.Context, acct *Account, isNew bool) error {
+	req, err := c.newRequest(ctx, http.MethodPost, "/show_website.php", url.Values{
+		"extjs":    {"1"},
+		"name":     {acct.Name},
+		"username": {acct.Username},
+		"password": {acct.Password},
+		"url":      {acct.URL},
+		"grouping": {acct.Group},
+		"extra":    {acct.Notes},
+		"aid":      {acct.ID},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := c.doMutation(req)
+	if err != nil {
+		return fmt.Errorf("lastpass: saving account: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("lastpass: reading save response: %w", err)
+	}
+
+	if !isNew && strings.Contains(string(body), "aid=\"0\"") {
+		return &AccountNotFoundError{ID: acct.ID}
+	}
+	if err := classifyWriteFailure(body, acct.Share); err != nil {
+		return err
+	}
+	return extractAID(body, acct)
+}
+
+func (c *Client) delete(ctx context.Context, acct *Account) error {
+	req, err := c.newRequest(ctx, http.MethodPost, "/show_website.php", url.Values{
+		"extjs":  {"1"},
+		"delete": {"1"},
+		"aid":    {acct.ID},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := c.doMutation(req)
+	if err != nil {
+		return fmt.Errorf("lastpass: deleting account: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("lastpass: reading delete response: %w", err)
+	}
+	if strings.Contains(string(body), "aid=\"0\"") {
+		return &AccountNotFoundError{ID: acct.ID}
+	}
+	return classifyWriteFailure(body, acct.Share)
+}
+
+// classifyWriteFailure maps a show_website.php response body to a typed,
+// errors.Is-friendly error. It understands both the legacy plain-text
+// messages LastPass's PHP endpoints still emit (e.g. the read-only share
+// rejection) and the XML <error cause="..."/> shape, funnelling both
+// through mapServerCause so new causes only need to be taught there.
+func classifyWriteFailure(body []byte, share string) error {
+	text := string(body)
+
+	if share != "" && strings.Contains(text, "cannot be written to read-only shared folder") {
+		return &ServerError{Cause: "readonly", Message: strings.TrimSpace(text)}
+	}
+
+	if parsed := parseServerError(body); parsed != nil {
+		return parsed
+	}
+	return nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, values url.Values) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("lastpass: building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if c.session != nil {
+		req.AddCookie(&http.Cookie{Name: "PHPSESSID", Value: c.session.ID})
+	}
+	return req, nil
+}
+
+// online reports whether the client believes it can currently reach
+// LastPass. It is only consulted when an OfflineQueue is attached. As a
+// side effect, it lazily refreshes Capabilities() from the response, the
+// same way login does.
+func (c *Client) online(ctx context.Context) bool {
+	req, err := c.newRequest(ctx, http.MethodGet, "/login_check.php", nil)
+	if err != nil {
+		return false
+	}
+	resp, err := c.doIdempotent(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false
+	}
+
+	caps := c.capabilities.get()
+	caps.AccountsVersion = extractAttr(string(body), "accts_version")
+	c.capabilities.set(caps)
+	return true
+}
+
+func extractAID(body []byte, acct *Account) error {
+	const marker = `aid="`
+	i := strings.Index(string(body), marker)
+	if i < 0 {
+		return fmt.Errorf("lastpass: could not find account id in response")
+	}
+	rest := string(body)[i+len(marker):]
+	j := strings.IndexByte(rest, '"')
+	if j < 0 {
+		return fmt.Errorf("lastpass: malformed account id in response")
+	}
+	acct.ID = rest[:j]
+	return nil
+}
+
+func (c *Client) login(ctx context.Context, username, password string) (*Session, error) {
+	iterations := c.fetchIterations(ctx, username)
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/login_check.php", url.Values{
+		"username": {username},
+		"password": {password},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doIdempotent(req)
+	if err != nil {
+		return nil, fmt.Errorf("lastpass: logging in: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("lastpass: reading login response: %w", err)
+	}
+
+	if serverErr := parseServerError(body); serverErr != nil {
+		if errors.Is(serverErr, ErrOutOfBandRequired) {
+			c.capabilities.set(Capabilities{
+				PBKDF2Iterations: iterations,
+				MFAProviders:     splitCommaList(extractAttr(string(body), "mfaproviders")),
+			})
+			if c.Capabilities().Supports(CapOutOfBandMultifactor) {
+				return c.completeOutOfBandLogin(ctx, username, password, iterations)
+			}
+		}
+		return nil, serverErr
+	}
+
+	return c.finishLogin(resp, body, username, password, iterations), nil
+}
+
+// outOfBandPollInterval/outOfBandMaxAttempts bound how long
+// completeOutOfBandLogin waits for the user to approve an out-of-band
+// multifactor push before giving up. outOfBandPollInterval is a var, not a
+// const, so tests can shorten it rather than actually waiting.
+var outOfBandPollInterval = 3 * time.Second
+
+const outOfBandMaxAttempts = 20
+
+// completeOutOfBandLogin polls login_check.php for an account that has
+// approved out-of-band multifactor (Capabilities().Supports(CapOutOfBandMultifactor)),
+// e.g. a push notification the user approves on another device, instead of
+// failing immediately with ErrOutOfBandRequired.
+func (c *Client) completeOutOfBandLogin(ctx context.Context, username, password string, iterations int) (*Session, error) {
+	for attempt := 0; attempt < outOfBandMaxAttempts; attempt++ {
+		if !sleepOrDone(ctx, outOfBandPollInterval) {
+			return nil, ctx.Err()
+		}
+
+		req, err := c.newRequest(ctx, http.MethodPost, "/login_check.php", url.Values{
+			"username":         {username},
+			"password":         {password},
+			"outofbandrequest": {"1"},
+			"outofbandretry":   {"1"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.doIdempotent(req)
+		if err != nil {
+			return nil, fmt.Errorf("lastpass: polling out-of-band login: %w", err)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("lastpass: reading out-of-band login response: %w", err)
+		}
+
+		if serverErr := parseServerError(body); serverErr != nil {
+			if errors.Is(serverErr, ErrOutOfBandRequired) {
+				continue
+			}
+			return nil, serverErr
+		}
+		return c.finishLogin(resp, body, username, password, iterations), nil
+	}
+	return nil, ErrOutOfBandRequired
+}
+
+// finishLogin builds the Session and caches Capabilities from a successful
+// login_check.php response body, shared by the regular and out-of-band
+// login paths.
+func (c *Client) finishLogin(resp *http.Response, body []byte, username, password string, iterations int) *Session {
+	accountsVersion := extractAttr(string(body), "accts_version")
+	caps := c.capabilities.get()
+	caps.AccountsVersion = accountsVersion
+	caps.PBKDF2Iterations = iterations
+	c.capabilities.set(caps)
+
+	var sessionID string
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "PHPSESSID" {
+			sessionID = cookie.Value
+		}
+	}
+
+	return &Session{
+		ID:              sessionID,
+		Username:        username,
+		EncryptionKey:   deriveEncryptionKey(username, password, iterations),
+		AccountsVersion: accountsVersion,
+	}
+}
+
+// splitCommaList splits a comma-separated attribute value into its parts,
+// e.g. mfaproviders="outofband,googleauth". An empty string yields nil.
+func splitCommaList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// defaultPBKDF2Iterations is used when iterations.php is unreachable or the
+// account predates that endpoint.
+const defaultPBKDF2Iterations = 100100
+
+// fetchIterations asks the server how many PBKDF2 rounds this account's
+// master password key derivation needs. login derives the key with this
+// round count, rather than a hard-coded one, and records it in
+// Capabilities().PBKDF2Iterations, since it varies per account and has
+// changed over time.
+func (c *Client) fetchIterations(ctx context.Context, username string) int {
+	req, err := c.newRequest(ctx, http.MethodPost, "/iterations.php", url.Values{
+		"email": {username},
+	})
+	if err != nil {
+		return defaultPBKDF2Iterations
+	}
+	resp, err := c.doIdempotent(req)
+	if err != nil {
+		return defaultPBKDF2Iterations
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return defaultPBKDF2Iterations
+	}
+
+	var iterations int
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(body)), "%d", &iterations); err != nil || iterations <= 0 {
+		return defaultPBKDF2Iterations
+	}
+	return iterations
+}
+
+func extractAttr(body, attr string) string {
+	marker := attr + `="`
+	i := strings.Index(body, marker)
+	if i < 0 {
+		return ""
+	}
+	rest := body[i+len(marker):]
+	j := strings.IndexByte(rest, '"')
+	if j < 0 {
+		return ""
+	}
+	return rest[:j]
+}
@@ -0,0 +1,107 @@
+package lastpass
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestClient_Login_CompletesOutOfBandMultifactor drives the path gated by
+// Capabilities().Supports(CapOutOfBandMultifactor): login_check.php first
+// reports that out-of-band approval is pending, and NewClient polls until
+// the user (simulated here) approves it on another device.
+func TestClient_Login_CompletesOutOfBandMultifactor(t *testing.T) {
+	originalInterval := outOfBandPollInterval
+	outOfBandPollInterval = time.Millisecond
+	defer func() { outOfBandPollInterval = originalInterval }()
+
+	var pollCount int
+	const approveAfter = 2
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/iterations.php":
+			fmt.Fprint(w, "100100")
+		case "/login_check.php":
+			if r.FormValue("outofbandrequest") != "1" {
+				fmt.Fprint(w, `<response><error cause="outofbandrequired" mfaproviders="outofband"/></response>`)
+				return
+			}
+			pollCount++
+			if pollCount < approveAfter {
+				fmt.Fprint(w, `<response><error cause="outofbandrequired" mfaproviders="outofband"/></response>`)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "PHPSESSID", Value: "sid"})
+			fmt.Fprint(w, `<response><ok accts_version="301"/></response>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(context.Background(), "user@example.com", "pass",
+		WithHTTPClient(rewriteToServerClient{server}))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	session, err := client.Session()
+	if err != nil {
+		t.Fatalf("Session: %v", err)
+	}
+	if session.ID != "sid" {
+		t.Errorf("session.ID = %q, want %q", session.ID, "sid")
+	}
+	if pollCount < approveAfter {
+		t.Fatalf("pollCount = %d, want at least %d (login must actually poll)", pollCount, approveAfter)
+	}
+	if !client.Capabilities().Supports(CapOutOfBandMultifactor) {
+		t.Error("Capabilities().Supports(CapOutOfBandMultifactor) = false after an out-of-band login")
+	}
+}
+
+// TestClient_Login_OutOfBandNotSupportedFailsImmediately asserts the gate's
+// other branch: an account without the out-of-band provider must not poll
+// at all, since there is nothing to approve.
+func TestClient_Login_OutOfBandNotSupportedFailsImmediately(t *testing.T) {
+	var polled bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/iterations.php":
+			fmt.Fprint(w, "100100")
+		case "/login_check.php":
+			if r.FormValue("outofbandrequest") == "1" {
+				polled = true
+			}
+			fmt.Fprint(w, `<response><error cause="outofbandrequired" mfaproviders="googleauth"/></response>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	_, err := NewClient(context.Background(), "user@example.com", "pass",
+		WithHTTPClient(rewriteToServerClient{server}))
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if polled {
+		t.Fatal("login polled for out-of-band approval on an account without the out-of-band provider")
+	}
+}
+
+// rewriteToServerClient proxies every request to server, the way
+// flakyHTTPClient does in queue_test.go.
+type rewriteToServerClient struct {
+	server *httptest.Server
+}
+
+func (c rewriteToServerClient) Do(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = "http"
+	req.URL.Host = c.server.Listener.Addr().String()
+	return http.DefaultClient.Do(req)
+}
@@ -0,0 +1,55 @@
+package lastpass
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"fmt"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// deriveEncryptionKey derives the AES key LastPass uses to encrypt/decrypt
+// vault data from the account's master username and password, following the
+// same PBKDF2-SHA256 scheme as the official clients.
+func deriveEncryptionKey(username, password string, iterations int) []byte {
+	return pbkdf2.Key([]byte(password), []byte(username), iterations, 32, sha256.New)
+}
+
+// decryptAES256CBC decrypts data that LastPass encrypted with AES-256 in CBC
+// mode, where the first aes.BlockSize bytes of data are the IV.
+func decryptAES256CBC(key, data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if len(data) < aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("lastpass: ciphertext has invalid length %d", len(data))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("lastpass: %w", err)
+	}
+
+	iv, ciphertext := data[:aes.BlockSize], data[aes.BlockSize:]
+	if len(ciphertext) == 0 {
+		return nil, nil
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	return unpad(plaintext)
+}
+
+// unpad strips PKCS#7 padding.
+func unpad(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	padLen := int(data[len(data)-1])
+	if padLen == 0 || padLen > len(data) {
+		return nil, fmt.Errorf("lastpass: invalid padding")
+	}
+	return data[:len(data)-padLen], nil
+}
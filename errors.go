@@ -0,0 +1,126 @@
+package lastpass
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// Sentinel errors every failure mode below can be tested for with
+// errors.Is, regardless of which concrete error type wraps it.
+var (
+	ErrAccountNotFound      = newSentinelError("account not found")
+	ErrReadOnlyShare        = newSentinelError("account cannot be written to read-only share")
+	ErrAuthenticationFailed = newSentinelError("authentication failed")
+	ErrOutOfBandRequired    = newSentinelError("out-of-band authentication required")
+	ErrOutOfBandRejected    = newSentinelError("out-of-band authentication rejected")
+	ErrSessionExpired       = newSentinelError("session expired")
+	ErrInvalidPassword      = newSentinelError("invalid master password")
+	ErrMultifactorRequired  = newSentinelError("multifactor authentication required")
+)
+
+// sentinelError is a plain, comparable error value so the package's
+// Err* variables work with both errors.Is and == comparison.
+type sentinelError struct{ text string }
+
+func newSentinelError(text string) error { return &sentinelError{text: "lastpass: " + text} }
+func (e *sentinelError) Error() string    { return e.text }
+
+// AccountNotFoundError is returned by Update and Delete when the given
+// Account's ID does not exist in the vault.
+type AccountNotFoundError struct {
+	ID string
+}
+
+func (e *AccountNotFoundError) Error() string {
+	return fmt.Sprintf("lastpass: account not found: %s", e.ID)
+}
+
+// Is reports whether target is ErrAccountNotFound, so callers can write
+// errors.Is(err, lastpass.ErrAccountNotFound) instead of type-asserting.
+func (e *AccountNotFoundError) Is(target error) bool {
+	return target == ErrAccountNotFound
+}
+
+// Is reports whether target is ErrReadOnlyShare.
+func (e *ReadOnlyShareError) Is(target error) bool {
+	return target == ErrReadOnlyShare
+}
+
+// ServerError wraps a failure LastPass reported as an <error cause="..."/>
+// XML element: Cause is the raw machine-readable code, Message is the
+// human-readable text the server sent, and RetryAfter is set when the
+// server asked the caller to back off (e.g. rate limiting).
+//
+// Unwrap maps Cause to one of the package's sentinel errors via
+// mapServerCause, so errors.Is(err, lastpass.ErrAuthenticationFailed) works
+// without callers ever seeing the raw cause string.
+type ServerError struct {
+	Code       string
+	Cause      string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *ServerError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("lastpass: %s", e.Message)
+	}
+	return fmt.Sprintf("lastpass: server error (cause=%s)", e.Cause)
+}
+
+func (e *ServerError) Unwrap() error {
+	return mapServerCause(e.Cause)
+}
+
+// mapServerCause is the single place new LastPass error causes get wired
+// up to a package sentinel. Add new codes here only; ServerError.Unwrap
+// and every caller that constructs a ServerError go through this.
+func mapServerCause(cause string) error {
+	switch cause {
+	case "unknownemail", "unknownpassword", "badpassword":
+		return ErrAuthenticationFailed
+	case "invalidpassword":
+		return ErrInvalidPassword
+	case "outofbandrequired":
+		return ErrOutOfBandRequired
+	case "outofbandrejected", "userinteractionrequired":
+		return ErrOutOfBandRejected
+	case "multifactorresponsefailed", "googleauthrequired", "yubikeyrestricted":
+		return ErrMultifactorRequired
+	case "sessiontimeout", "sessioninvalid":
+		return ErrSessionExpired
+	case "readonly":
+		return ErrReadOnlyShare
+	default:
+		return nil
+	}
+}
+
+// xmlErrorResponse matches the <error cause="..." message="..."
+// retryafter="..."/> element most LastPass endpoints use to report a
+// failure. It deliberately has no XMLName field: different endpoints wrap
+// the same <error/> element in different root tags (<response>,
+// <xmlresponse>, ...), and only the child element is relevant here.
+type xmlErrorResponse struct {
+	Error *struct {
+		Cause      string `xml:"cause,attr"`
+		Message    string `xml:"message,attr"`
+		RetryAfter int    `xml:"retryafter,attr"`
+	} `xml:"error"`
+}
+
+// parseServerError extracts a *ServerError from body if it looks like a
+// LastPass <error .../> response, or returns nil if body does not contain
+// one (e.g. it was a successful response).
+func parseServerError(body []byte) *ServerError {
+	var parsed xmlErrorResponse
+	if err := xml.Unmarshal(body, &parsed); err != nil || parsed.Error == nil {
+		return nil
+	}
+	return &ServerError{
+		Cause:      parsed.Error.Cause,
+		Message:    parsed.Error.Message,
+		RetryAfter: time.Duration(parsed.Error.RetryAfter) * time.Second,
+	}
+}
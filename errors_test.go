@@ -0,0 +1,80 @@
+package lastpass
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestAccountNotFoundError_Is(t *testing.T) {
+	err := &AccountNotFoundError{ID: "123"}
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Fatal("errors.Is(err, ErrAccountNotFound) = false, want true")
+	}
+	// reflect.DeepEqual-based comparison (what gomega's MatchError uses for
+	// an error argument) must keep working for existing callers.
+	if !reflect.DeepEqual(err, &AccountNotFoundError{ID: "123"}) {
+		t.Fatal("struct equality for backwards-compatible matching should still work")
+	}
+}
+
+func TestServerError_UnwrapsToSentinel(t *testing.T) {
+	tests := []struct {
+		cause string
+		want  error
+	}{
+		{"unknownpassword", ErrAuthenticationFailed},
+		{"outofbandrequired", ErrOutOfBandRequired},
+		{"sessiontimeout", ErrSessionExpired},
+		{"readonly", ErrReadOnlyShare},
+	}
+	for _, tt := range tests {
+		err := &ServerError{Cause: tt.cause, Message: "server says no"}
+		if !errors.Is(err, tt.want) {
+			t.Errorf("cause %q: errors.Is(err, %v) = false, want true", tt.cause, tt.want)
+		}
+	}
+}
+
+func TestServerError_UnknownCauseDoesNotMatchAnySentinel(t *testing.T) {
+	err := &ServerError{Cause: "somethingnew"}
+	for _, sentinel := range []error{
+		ErrAccountNotFound, ErrReadOnlyShare, ErrAuthenticationFailed,
+		ErrOutOfBandRequired, ErrOutOfBandRejected, ErrSessionExpired,
+		ErrInvalidPassword, ErrMultifactorRequired,
+	} {
+		if errors.Is(err, sentinel) {
+			t.Errorf("unmapped cause unexpectedly matched %v", sentinel)
+		}
+	}
+}
+
+func TestAdd_PropagatesCancelledContext(t *testing.T) {
+	// The handler should never actually run: the context is cancelled
+	// before Add issues the request.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<xmlresponse><result aid="1" msg="accountupdated"></result></xmlresponse>`)
+	}))
+	defer server.Close()
+
+	client, err := NewClientFromSession(context.Background(), &Session{ID: "sid"},
+		WithHTTPClient(http.DefaultClient))
+	if err != nil {
+		t.Fatalf("NewClientFromSession: %v", err)
+	}
+	client.baseURL = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A context cancellation surfaced through the real http.Client must
+	// remain detectable with errors.Is even after Client.upsert wraps it.
+	err = client.Add(ctx, &Account{Name: "test"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("errors.Is(err, context.Canceled) = false, want true (err = %v)", err)
+	}
+}
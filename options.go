@@ -0,0 +1,54 @@
+package lastpass
+
+import "net/http"
+
+// HTTPClient is the subset of *http.Client that Client depends on. Tests
+// substitute it with fakes that buffer or replay requests.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// ClientOption configures a Client at construction time.
+type ClientOption func(*Client)
+
+// WithHTTPClient overrides the HTTP client used for all LastPass API calls.
+// The zero value is http.DefaultClient.
+func WithHTTPClient(httpClient HTTPClient) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithOfflineQueue attaches an OfflineQueue to the Client. Once attached,
+// Add/Update/Delete transparently buffer to the queue instead of failing
+// when the network is unreachable, and Accounts falls back to the queue's
+// cached snapshot. Call queue.Flush or queue.Sync once connectivity is
+// restored to replay buffered mutations.
+func WithOfflineQueue(queue *OfflineQueue) ClientOption {
+	return func(c *Client) {
+		c.queue = queue
+	}
+}
+
+// WithCapabilities freezes the Client's Capabilities to caps, so login and
+// any subsequent login_check.php probes never overwrite them. This is
+// meant for tests that stub out the HTTP transport and therefore have no
+// real server response to learn capabilities from.
+func WithCapabilities(caps Capabilities) ClientOption {
+	return func(c *Client) {
+		c.capabilities.freeze(caps)
+	}
+}
+
+// WithRetryPolicy installs policy on the Client: idempotent operations
+// (Accounts, FetchEncryptedAccounts, login checks) retry on 5xx responses
+// and network errors; Add/Update/Delete retry only on errors that occurred
+// before any request bytes reached the server, to avoid double-applying a
+// mutation. A context cancellation or deadline always aborts immediately,
+// regardless of remaining attempts.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		p := policy
+		c.retryPolicy = &p
+	}
+}
@@ -0,0 +1,225 @@
+package lastpass
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// operationKind identifies which Client method a queuedOperation replays.
+type operationKind int
+
+const (
+	opAdd operationKind = iota
+	opUpdate
+	opDelete
+)
+
+// queuedOperation is a single buffered mutation. ID is stable across
+// process restarts so that Flush can be interrupted mid-batch and resumed
+// without double-applying a mutation that already reached the server.
+type queuedOperation struct {
+	ID string
+	// Seq orders operations for replay; a QueueStore assigns it on first
+	// save if it is zero, so callers never need to set it themselves.
+	Seq  int64
+	Kind operationKind
+	// Account is a full snapshot of the account at enqueue time.
+	Account *Account
+	// BaseLastModifiedGMT is acct.LastModifiedGMT as it stood when the
+	// operation was enqueued, used to detect that the server copy changed
+	// out from under a queued Update/Delete.
+	BaseLastModifiedGMT string
+	// Applied is set once the mutation has actually succeeded against the
+	// server. It lets Flush resume correctly if the process crashes after
+	// that success but before the operation is cleared from the store:
+	// without it, a resumed Flush would either replay an Add as a
+	// duplicate, or re-check a since-changed Update/Delete against its now
+	// stale BaseLastModifiedGMT and misreport a conflict (or, for a Delete,
+	// re-issue it against an account the server no longer has).
+	Applied bool
+}
+
+// QueueStore persists an OfflineQueue's pending operations and its accounts
+// snapshot so both survive a process restart. FileQueueStore is the
+// built-in default; callers may supply their own, e.g. backed by BoltDB.
+type QueueStore interface {
+	SaveOperation(op *queuedOperation) error
+	Operations() ([]*queuedOperation, error)
+	DeleteOperation(id string) error
+
+	SaveAccountsSnapshot(blob []byte) error
+	LoadAccountsSnapshot() ([]byte, error)
+}
+
+// ConflictError is returned by Flush/Sync when a queued Update or Delete
+// targets an account that changed on the server after the mutation was
+// queued. Callers typically re-apply the queued edits on top of Server, or
+// discard them in favor of it.
+type ConflictError struct {
+	ID     string
+	Queued *Account
+	Server *Account
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("lastpass: account %s was modified on the server since it was queued", e.ID)
+}
+
+// OfflineQueue buffers Add/Update/Delete calls made while the network is
+// unreachable and replays them once the Client is reachable again. Attach
+// one to a Client with WithOfflineQueue.
+type OfflineQueue struct {
+	store QueueStore
+
+	mu sync.Mutex
+}
+
+// NewOfflineQueue creates a queue backed by store. Use NewFileQueueStore
+// for the default durable, disk-backed store.
+func NewOfflineQueue(store QueueStore) *OfflineQueue {
+	return &OfflineQueue{store: store}
+}
+
+// enqueue records a mutation for later replay. For Add, acct.ID is left
+// empty; the server assigns the real ID during Flush/Sync.
+func (q *OfflineQueue) enqueue(ctx context.Context, kind operationKind, acct *Account) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	id, err := newOperationID()
+	if err != nil {
+		return fmt.Errorf("lastpass: generating operation id: %w", err)
+	}
+
+	acctCopy := *acct
+	op := &queuedOperation{
+		ID:                  id,
+		Kind:                kind,
+		Account:             &acctCopy,
+		BaseLastModifiedGMT: acct.LastModifiedGMT,
+	}
+	return q.store.SaveOperation(op)
+}
+
+// Pending returns the number of buffered mutations not yet replayed.
+func (q *OfflineQueue) Pending() (int, error) {
+	ops, err := q.store.Operations()
+	if err != nil {
+		return 0, err
+	}
+	return len(ops), nil
+}
+
+// Flush replays every buffered mutation against client, in the order it
+// was queued, removing each from the store as it succeeds. Replay is
+// idempotent: an operation ID that the store no longer holds is assumed to
+// have already been applied and is skipped.
+//
+// If a queued Update or Delete targets an account whose LastModifiedGMT on
+// the server no longer matches BaseLastModifiedGMT, Flush stops and returns
+// a *ConflictError for that operation, leaving it (and anything queued
+// after it) in the store so the caller can resolve the conflict and retry.
+func (q *OfflineQueue) Flush(ctx context.Context, client *Client) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ops, err := q.store.Operations()
+	if err != nil {
+		return fmt.Errorf("lastpass: loading queued operations: %w", err)
+	}
+
+	for _, op := range ops {
+		if err := q.replay(ctx, client, op); err != nil {
+			return err
+		}
+		if err := q.store.DeleteOperation(op.ID); err != nil {
+			return fmt.Errorf("lastpass: clearing replayed operation %s: %w", op.ID, err)
+		}
+	}
+	return nil
+}
+
+// Sync is Flush followed by refreshing the queue's offline accounts
+// snapshot from the now-reachable server, so the next offline stretch
+// starts from current data.
+func (q *OfflineQueue) Sync(ctx context.Context, client *Client) error {
+	if err := q.Flush(ctx, client); err != nil {
+		return err
+	}
+	blob, err := client.FetchEncryptedAccounts(ctx)
+	if err != nil {
+		return fmt.Errorf("lastpass: refreshing offline accounts snapshot: %w", err)
+	}
+	return q.store.SaveAccountsSnapshot(blob)
+}
+
+// replay applies op against client, unless op.Applied already shows a prior
+// Flush completed it: a process can crash after a mutation reaches the
+// server but before Flush's DeleteOperation clears it from the store, and
+// the next Flush must treat that as done rather than re-applying it (which
+// would duplicate an Add, spuriously conflict a since-applied Update against
+// its own now-stale BaseLastModifiedGMT, or re-issue a Delete against an
+// account the server already removed).
+func (q *OfflineQueue) replay(ctx context.Context, client *Client, op *queuedOperation) error {
+	if op.Applied {
+		return nil
+	}
+
+	if op.Kind != opAdd {
+		if err := q.checkConflict(ctx, client, op); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch op.Kind {
+	case opAdd:
+		err = client.upsert(ctx, op.Account, true)
+	case opUpdate:
+		err = client.upsert(ctx, op.Account, false)
+	case opDelete:
+		err = client.delete(ctx, op.Account)
+	default:
+		return fmt.Errorf("lastpass: unknown queued operation kind %d", op.Kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Persist success (and, for Add, the server-assigned ID client.upsert
+	// just wrote into op.Account) before Flush clears the operation, so a
+	// crash right here still resumes correctly.
+	op.Applied = true
+	return q.store.SaveOperation(op)
+}
+
+// checkConflict compares the queued account's base version against the
+// server's current copy, so a stale Update/Delete surfaces as a
+// ConflictError instead of silently clobbering a newer server-side change.
+func (q *OfflineQueue) checkConflict(ctx context.Context, client *Client, op *queuedOperation) error {
+	accounts, err := client.Accounts(ctx)
+	if err != nil {
+		return fmt.Errorf("lastpass: checking for conflicts: %w", err)
+	}
+	for _, server := range accounts {
+		if server.ID != op.Account.ID {
+			continue
+		}
+		if server.LastModifiedGMT != op.BaseLastModifiedGMT {
+			return &ConflictError{ID: op.Account.ID, Queued: op.Account, Server: server}
+		}
+		return nil
+	}
+	return nil
+}
+
+func newOperationID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,472 @@
+package lastpass
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// flakyHTTPClient proxies to an httptest.Server but fails every request
+// while offline is true, simulating a lost connection.
+type flakyHTTPClient struct {
+	server  *httptest.Server
+	offline bool
+}
+
+func (c *flakyHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if c.offline {
+		return nil, fmt.Errorf("simulated network loss")
+	}
+	req.URL.Scheme = "http"
+	req.URL.Host = c.server.Listener.Addr().String()
+	return http.DefaultClient.Do(req)
+}
+
+func newTestServer(t *testing.T, nextID *int, saved *[]string, deleted *[]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login_check.php":
+			fmt.Fprint(w, `<response><ok accts_version="111"/></response>`)
+		case "/show_website.php":
+			if r.FormValue("delete") == "1" {
+				*deleted = append(*deleted, r.FormValue("aid"))
+				fmt.Fprint(w, `<xmlresponse><result aid="`+r.FormValue("aid")+`" msg="accountdeleted"></result></xmlresponse>`)
+				return
+			}
+			*nextID++
+			id := fmt.Sprintf("%d", *nextID)
+			*saved = append(*saved, r.FormValue("name"))
+			fmt.Fprint(w, `<xmlresponse><result aid="`+id+`" msg="accountupdated"></result></xmlresponse>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestOfflineQueue_ReplaysBufferedMutationsInOrder(t *testing.T) {
+	var nextID int
+	var saved, deleted []string
+	server := newTestServer(t, &nextID, &saved, &deleted)
+	defer server.Close()
+
+	httpClient := &flakyHTTPClient{server: server}
+	store, err := NewFileQueueStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileQueueStore: %v", err)
+	}
+	queue := NewOfflineQueue(store)
+
+	client, err := NewClientFromSession(context.Background(), &Session{
+		ID:            "sid",
+		Username:      "user",
+		EncryptionKey: deriveEncryptionKey("user", "pass", 1),
+	}, WithHTTPClient(httpClient), WithOfflineQueue(queue))
+	if err != nil {
+		t.Fatalf("NewClientFromSession: %v", err)
+	}
+
+	// Go offline mid-batch: the first two Adds queue, the network then
+	// comes back before Flush.
+	httpClient.offline = true
+
+	first := &Account{Name: "first"}
+	second := &Account{Name: "second"}
+	if err := client.Add(context.Background(), first); err != nil {
+		t.Fatalf("Add(first): %v", err)
+	}
+	if err := client.Add(context.Background(), second); err != nil {
+		t.Fatalf("Add(second): %v", err)
+	}
+
+	pending, err := queue.Pending()
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if pending != 2 {
+		t.Fatalf("Pending() = %d, want 2", pending)
+	}
+
+	httpClient.offline = false
+	if err := queue.Flush(context.Background(), client); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	if got, want := saved, []string{"first", "second"}; !equalStrings(got, want) {
+		t.Fatalf("replayed adds = %v, want %v (order must match enqueue order)", got, want)
+	}
+
+	pending, err = queue.Pending()
+	if err != nil {
+		t.Fatalf("Pending after flush: %v", err)
+	}
+	if pending != 0 {
+		t.Fatalf("Pending() after Flush = %d, want 0", pending)
+	}
+}
+
+// TestOfflineQueue_FlushDetectsServerSideConflict exercises checkConflict
+// end to end: the server's copy of an account has a newer LastModifiedGMT
+// than the queued Update's BaseLastModifiedGMT, so Flush must stop and
+// return a *ConflictError instead of clobbering the server-side change.
+func TestOfflineQueue_FlushDetectsServerSideConflict(t *testing.T) {
+	key := deriveEncryptionKey("user", "pass", 1)
+	blob := buildAccountsBlob(key, []*Account{
+		{ID: "42", Name: "Server Copy", LastModifiedGMT: "2000"},
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login_check.php":
+			fmt.Fprint(w, `<response><ok accts_version="111"/></response>`)
+		case "/getaccts.php":
+			w.Write(blob)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	store, err := NewFileQueueStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileQueueStore: %v", err)
+	}
+	queue := NewOfflineQueue(store)
+
+	client, err := NewClientFromSession(context.Background(), &Session{
+		ID:            "sid",
+		Username:      "user",
+		EncryptionKey: key,
+	}, WithHTTPClient(rewriteToServerClient{server}), WithOfflineQueue(queue))
+	if err != nil {
+		t.Fatalf("NewClientFromSession: %v", err)
+	}
+
+	queuedAcct := &Account{ID: "42", Name: "Local Edit", LastModifiedGMT: "1000"}
+	if err := queue.enqueue(context.Background(), opUpdate, queuedAcct); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	err = queue.Flush(context.Background(), client)
+	var conflictErr *ConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("Flush error = %v, want *ConflictError", err)
+	}
+	if conflictErr.ID != "42" {
+		t.Errorf("ConflictError.ID = %q, want %q", conflictErr.ID, "42")
+	}
+	if conflictErr.Server.LastModifiedGMT != "2000" {
+		t.Errorf("ConflictError.Server.LastModifiedGMT = %q, want %q", conflictErr.Server.LastModifiedGMT, "2000")
+	}
+
+	// The conflicting operation must stay queued so the caller can resolve
+	// it (re-apply on top of Server, or discard it) and retry.
+	pending, err := queue.Pending()
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if pending != 1 {
+		t.Fatalf("Pending() after a conflicting Flush = %d, want 1", pending)
+	}
+}
+
+// TestClient_Accounts_FallsBackToOfflineSnapshot covers the other half of
+// WithOfflineQueue: Accounts must serve the queue's cached snapshot instead
+// of failing outright when the network is unreachable.
+func TestClient_Accounts_FallsBackToOfflineSnapshot(t *testing.T) {
+	key := deriveEncryptionKey("user", "pass", 1)
+	blob := buildAccountsBlob(key, []*Account{
+		{ID: "1", Name: "Cached Account", LastModifiedGMT: "1000"},
+	})
+
+	store, err := NewFileQueueStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileQueueStore: %v", err)
+	}
+	if err := store.SaveAccountsSnapshot(blob); err != nil {
+		t.Fatalf("SaveAccountsSnapshot: %v", err)
+	}
+	queue := NewOfflineQueue(store)
+
+	client, err := NewClientFromSession(context.Background(), &Session{
+		ID:            "sid",
+		Username:      "user",
+		EncryptionKey: key,
+	}, WithHTTPClient(&flakyHTTPClient{offline: true}), WithOfflineQueue(queue))
+	if err != nil {
+		t.Fatalf("NewClientFromSession: %v", err)
+	}
+
+	accounts, err := client.Accounts(context.Background())
+	if err != nil {
+		t.Fatalf("Accounts: %v", err)
+	}
+	if len(accounts) != 1 || accounts[0].Name != "Cached Account" {
+		t.Fatalf("Accounts() = %+v, want a single Cached Account", accounts)
+	}
+}
+
+// simulateCrashAfterApply marks store's single queued operation as already
+// applied against the server, the way replay leaves it right after a
+// mutation succeeds but before Flush's DeleteOperation clears it — i.e. as
+// if the process had crashed in that window.
+func simulateCrashAfterApply(t *testing.T, store *FileQueueStore, serverAssignedID string) {
+	t.Helper()
+	ops, err := store.Operations()
+	if err != nil {
+		t.Fatalf("Operations: %v", err)
+	}
+	if len(ops) != 1 {
+		t.Fatalf("Operations() = %d entries, want 1", len(ops))
+	}
+	ops[0].Applied = true
+	if serverAssignedID != "" {
+		ops[0].Account.ID = serverAssignedID
+	}
+	if err := store.SaveOperation(ops[0]); err != nil {
+		t.Fatalf("SaveOperation: %v", err)
+	}
+}
+
+// TestOfflineQueue_FlushDoesNotDuplicateAddAfterCrash covers the gap where a
+// process crashes after an Add reaches the server but before Flush clears
+// the operation from the store: resuming Flush must recognize the operation
+// as already applied and skip re-creating the account, rather than
+// replaying it as a duplicate Add.
+func TestOfflineQueue_FlushDoesNotDuplicateAddAfterCrash(t *testing.T) {
+	var nextID int
+	var saved, deleted []string
+	server := newTestServer(t, &nextID, &saved, &deleted)
+	defer server.Close()
+
+	store, err := NewFileQueueStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileQueueStore: %v", err)
+	}
+	queue := NewOfflineQueue(store)
+
+	client, err := NewClientFromSession(context.Background(), &Session{
+		ID:            "sid",
+		Username:      "user",
+		EncryptionKey: deriveEncryptionKey("user", "pass", 1),
+	}, WithHTTPClient(rewriteToServerClient{server}), WithOfflineQueue(queue))
+	if err != nil {
+		t.Fatalf("NewClientFromSession: %v", err)
+	}
+
+	if err := queue.enqueue(context.Background(), opAdd, &Account{Name: "crash-test"}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	simulateCrashAfterApply(t, store, "already-created")
+
+	if err := queue.Flush(context.Background(), client); err != nil {
+		t.Fatalf("Flush resuming after the simulated crash: %v", err)
+	}
+	if len(saved) != 0 {
+		t.Fatalf("saved = %v, want no create requests (the account was already created before the crash)", saved)
+	}
+
+	pending, err := queue.Pending()
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if pending != 0 {
+		t.Fatalf("Pending() = %d, want 0", pending)
+	}
+}
+
+// TestOfflineQueue_FlushDoesNotReapplyUpdateAfterCrash covers the same crash
+// window for Update: without Applied, a resumed Flush would re-check the
+// operation's now-stale BaseLastModifiedGMT against the server's (already
+// updated) copy and misreport a *ConflictError forever.
+func TestOfflineQueue_FlushDoesNotReapplyUpdateAfterCrash(t *testing.T) {
+	var nextID int
+	var saved, deleted []string
+	server := newTestServer(t, &nextID, &saved, &deleted)
+	defer server.Close()
+
+	key := deriveEncryptionKey("user", "pass", 1)
+	blob := buildAccountsBlob(key, []*Account{
+		{ID: "7", Name: "Updated Elsewhere", LastModifiedGMT: "2000"},
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/", server.Config.Handler)
+	mux.HandleFunc("/getaccts.php", func(w http.ResponseWriter, r *http.Request) { w.Write(blob) })
+	server.Config.Handler = mux
+
+	store, err := NewFileQueueStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileQueueStore: %v", err)
+	}
+	queue := NewOfflineQueue(store)
+
+	client, err := NewClientFromSession(context.Background(), &Session{
+		ID:            "sid",
+		Username:      "user",
+		EncryptionKey: key,
+	}, WithHTTPClient(rewriteToServerClient{server}), WithOfflineQueue(queue))
+	if err != nil {
+		t.Fatalf("NewClientFromSession: %v", err)
+	}
+
+	// BaseLastModifiedGMT is deliberately stale relative to the blob above:
+	// that's what the operation's own (already applied) write left behind.
+	queuedAcct := &Account{ID: "7", Name: "Updated Elsewhere", LastModifiedGMT: "1000"}
+	if err := queue.enqueue(context.Background(), opUpdate, queuedAcct); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	simulateCrashAfterApply(t, store, "")
+
+	if err := queue.Flush(context.Background(), client); err != nil {
+		t.Fatalf("Flush resuming after the simulated crash: %v", err)
+	}
+	if len(saved) != 0 {
+		t.Fatalf("saved = %v, want no update requests (already applied before the crash)", saved)
+	}
+
+	pending, err := queue.Pending()
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if pending != 0 {
+		t.Fatalf("Pending() = %d, want 0", pending)
+	}
+}
+
+// TestOfflineQueue_FlushDoesNotReapplyDeleteAfterCrash covers the same crash
+// window for Delete: without Applied, a resumed Flush would re-issue the
+// delete against an account the server no longer has, get back an
+// AccountNotFoundError, and leave the operation stuck in the store forever.
+func TestOfflineQueue_FlushDoesNotReapplyDeleteAfterCrash(t *testing.T) {
+	var nextID int
+	var saved, deleted []string
+	server := newTestServer(t, &nextID, &saved, &deleted)
+	defer server.Close()
+
+	key := deriveEncryptionKey("user", "pass", 1)
+	// The account is already gone from the server, as a prior, crashed
+	// Flush would have left it.
+	blob := buildAccountsBlob(key, nil)
+	mux := http.NewServeMux()
+	mux.Handle("/", server.Config.Handler)
+	mux.HandleFunc("/getaccts.php", func(w http.ResponseWriter, r *http.Request) { w.Write(blob) })
+	server.Config.Handler = mux
+
+	store, err := NewFileQueueStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileQueueStore: %v", err)
+	}
+	queue := NewOfflineQueue(store)
+
+	client, err := NewClientFromSession(context.Background(), &Session{
+		ID:            "sid",
+		Username:      "user",
+		EncryptionKey: key,
+	}, WithHTTPClient(rewriteToServerClient{server}), WithOfflineQueue(queue))
+	if err != nil {
+		t.Fatalf("NewClientFromSession: %v", err)
+	}
+
+	queuedAcct := &Account{ID: "9", Name: "Deleted Elsewhere", LastModifiedGMT: "1000"}
+	if err := queue.enqueue(context.Background(), opDelete, queuedAcct); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	simulateCrashAfterApply(t, store, "")
+
+	if err := queue.Flush(context.Background(), client); err != nil {
+		t.Fatalf("Flush resuming after the simulated crash: %v", err)
+	}
+	if len(deleted) != 0 {
+		t.Fatalf("deleted = %v, want no delete requests (already applied before the crash)", deleted)
+	}
+
+	pending, err := queue.Pending()
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if pending != 0 {
+		t.Fatalf("Pending() = %d, want 0", pending)
+	}
+}
+
+// buildAccountsBlob encodes accounts as a getaccts.php-shaped blob: one
+// "ACCT" chunk per account, encrypted with key the same way the real
+// endpoint would be, so ParseEncryptedAccounts can decrypt it unmodified.
+func buildAccountsBlob(key []byte, accounts []*Account) []byte {
+	var buf bytes.Buffer
+	for _, acct := range accounts {
+		payload := buildAccountChunkPayload(currentAccountChunkFieldNames, map[string]string{
+			"id":                acct.ID,
+			"name":              encryptFieldForTest(key, acct.Name),
+			"group":             acct.Group,
+			"url":               encodeHexURLForTest(acct.URL),
+			"notes":             encryptFieldForTest(key, acct.Notes),
+			"username":          encryptFieldForTest(key, acct.Username),
+			"password":          encryptFieldForTest(key, acct.Password),
+			"last_modified_gmt": acct.LastModifiedGMT,
+			"last_touch":        acct.LastTouch,
+		})
+
+		buf.WriteString("ACCT")
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(payload)))
+		buf.Write(length[:])
+		buf.Write(payload)
+	}
+	return buf.Bytes()
+}
+
+// encryptFieldForTest matches decryptField's expectation: base64 of a
+// random IV followed by AES-256-CBC ciphertext.
+func encryptFieldForTest(key []byte, plaintext string) string {
+	padded := pkcs7Pad([]byte(plaintext), aes.BlockSize)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		panic(err)
+	}
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+	return base64.StdEncoding.EncodeToString(append(iv, ciphertext...))
+}
+
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	padLen := blockSize - len(data)%blockSize
+	return append(data, bytes.Repeat([]byte{byte(padLen)}, padLen)...)
+}
+
+// encodeHexURLForTest matches decodeHexURL's expectation: each byte of the
+// URL as a two-digit hex pair.
+func encodeHexURLForTest(url string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(url); i++ {
+		fmt.Fprintf(&buf, "%02x", url[i])
+	}
+	return buf.String()
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
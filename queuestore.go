@@ -0,0 +1,163 @@
+package lastpass
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// FileQueueStore is the default QueueStore: pending operations are kept as
+// one JSON file per operation, and the accounts snapshot as a single blob
+// file, all under Dir. It is safe for concurrent use by a single process.
+type FileQueueStore struct {
+	dir string
+	mu  sync.Mutex
+
+	lastSeq int64
+}
+
+// NewFileQueueStore returns a FileQueueStore rooted at dir, creating dir if
+// it does not already exist.
+func NewFileQueueStore(dir string) (*FileQueueStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("lastpass: creating queue store directory: %w", err)
+	}
+	store := &FileQueueStore{dir: dir}
+
+	ops, err := store.Operations()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		if op.Seq > store.lastSeq {
+			store.lastSeq = op.Seq
+		}
+	}
+	return store, nil
+}
+
+func (s *FileQueueStore) opPath(id string) string {
+	return filepath.Join(s.dir, "op-"+id+".json")
+}
+
+func (s *FileQueueStore) snapshotPath() string {
+	return filepath.Join(s.dir, "accounts-snapshot.bin")
+}
+
+// SaveOperation persists op, overwriting any previous operation with the
+// same ID.
+func (s *FileQueueStore) SaveOperation(op *queuedOperation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if op.Seq == 0 {
+		s.lastSeq++
+		op.Seq = s.lastSeq
+	}
+
+	data, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Errorf("lastpass: encoding queued operation: %w", err)
+	}
+	if err := atomicWriteFile(s.dir, s.opPath(op.ID), data, 0o600); err != nil {
+		return fmt.Errorf("lastpass: writing queued operation: %w", err)
+	}
+	return nil
+}
+
+// Operations returns all pending operations ordered by Seq, i.e. the order
+// they were originally enqueued in, regardless of filesystem directory
+// order.
+func (s *FileQueueStore) Operations() ([]*queuedOperation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("lastpass: listing queue store: %w", err)
+	}
+
+	var ops []*queuedOperation
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("lastpass: reading queued operation %s: %w", entry.Name(), err)
+		}
+		var op queuedOperation
+		if err := json.Unmarshal(data, &op); err != nil {
+			return nil, fmt.Errorf("lastpass: decoding queued operation %s: %w", entry.Name(), err)
+		}
+		ops = append(ops, &op)
+	}
+
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Seq < ops[j].Seq })
+	return ops, nil
+}
+
+// DeleteOperation removes a previously saved operation. Deleting an ID
+// that is not present is not an error, so Flush can be safely retried.
+func (s *FileQueueStore) DeleteOperation(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.opPath(id))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("lastpass: deleting queued operation %s: %w", id, err)
+	}
+	return nil
+}
+
+// SaveAccountsSnapshot overwrites the cached, still-encrypted accounts blob
+// used to serve Client.Accounts while offline.
+func (s *FileQueueStore) SaveAccountsSnapshot(blob []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := atomicWriteFile(s.dir, s.snapshotPath(), blob, 0o600); err != nil {
+		return fmt.Errorf("lastpass: writing accounts snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadAccountsSnapshot returns the last blob saved by SaveAccountsSnapshot.
+func (s *FileQueueStore) LoadAccountsSnapshot() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blob, err := os.ReadFile(s.snapshotPath())
+	if err != nil {
+		return nil, fmt.Errorf("lastpass: reading accounts snapshot: %w", err)
+	}
+	return blob, nil
+}
+
+// atomicWriteFile writes data to path by writing to a temp file in dir and
+// renaming it over path, so a crash or power loss mid-write cannot leave a
+// truncated file behind for the next Operations/LoadAccountsSnapshot to
+// choke on.
+func atomicWriteFile(dir, path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
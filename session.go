@@ -0,0 +1,12 @@
+package lastpass
+
+// Session is the subset of login state needed to resume talking to LastPass
+// without re-authenticating: the session cookie LastPass issued and the
+// account's encryption key. It can be persisted by callers (e.g. alongside
+// an OfflineQueue's store) and handed to NewClientFromSession later.
+type Session struct {
+	ID              string
+	Username        string
+	EncryptionKey   []byte
+	AccountsVersion string
+}
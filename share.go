@@ -0,0 +1,258 @@
+package lastpass
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Share describes a shared folder the logged in user belongs to, along
+// with the permissions LastPass granted them on it.
+type Share struct {
+	Name                   string
+	ID                     string
+	ReadOnly               bool
+	HidePasswords          bool
+	AdminUser              bool
+	GivePermissionToOthers bool
+}
+
+// ShareUser is a single member of a shared folder, as returned by
+// Client.ShareUsers.
+type ShareUser struct {
+	Email                  string
+	ReadOnly               bool
+	Admin                  bool
+	HidePasswords          bool
+	GivePermissionToOthers bool
+}
+
+// SharePermissions is the set of flags Client.SetSharePermissions grants or
+// revokes for a single member of a shared folder.
+type SharePermissions struct {
+	ReadOnly               bool
+	Admin                  bool
+	HidePasswords          bool
+	GivePermissionToOthers bool
+}
+
+// ReadOnlyShareError is returned by Add/Update/Delete when the account's
+// shared folder only grants the caller read access.
+type ReadOnlyShareError struct {
+	Share string
+}
+
+func (e *ReadOnlyShareError) Error() string {
+	return fmt.Sprintf("lastpass: account cannot be written to read-only shared folder %s", e.Share)
+}
+
+// HiddenPasswordShareError is returned by Add/Update when the account's
+// shared folder hides passwords from the caller: the client holds no
+// decryption material for that share's password field, so it has no
+// plaintext password to send the server in the first place.
+type HiddenPasswordShareError struct {
+	Share string
+}
+
+func (e *HiddenPasswordShareError) Error() string {
+	return fmt.Sprintf("lastpass: passwords are hidden in shared folder %s", e.Share)
+}
+
+// SharePermissionDeniedError is returned by ShareUsers/SetSharePermissions
+// when the caller is not an admin of the shared folder.
+type SharePermissionDeniedError struct {
+	Share  string
+	Action string
+}
+
+func (e *SharePermissionDeniedError) Error() string {
+	return fmt.Sprintf("lastpass: not permitted to %s shared folder %s", e.Action, e.Share)
+}
+
+type sharedFolderInfoResponse struct {
+	XMLName xml.Name        `xml:"response"`
+	Shares  []shareXMLEntry `xml:"sharedfolder"`
+}
+
+type shareXMLEntry struct {
+	Name                   string `xml:"name,attr"`
+	ID                     string `xml:"id,attr"`
+	ReadOnly               bool   `xml:"readonly,attr"`
+	HidePasswords          bool   `xml:"give_hide_passwords,attr"`
+	AdminUser              bool   `xml:"admin,attr"`
+	GivePermissionToOthers bool   `xml:"give_permission,attr"`
+}
+
+// Shares returns the shared folders visible to the logged in user and
+// refreshes the Client's internal share permission cache, which
+// Add/Update/Delete consult to reject writes client-side before making an
+// HTTP round trip.
+func (c *Client) Shares(ctx context.Context) ([]*Share, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/getSharedFolderInfo.php", url.Values{
+		"lpversion": {"1"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doIdempotent(req)
+	if err != nil {
+		return nil, fmt.Errorf("lastpass: fetching shared folders: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("lastpass: reading shared folders response: %w", err)
+	}
+
+	var parsed sharedFolderInfoResponse
+	if err := xml.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("lastpass: parsing shared folders response: %w", err)
+	}
+
+	shares := make([]*Share, 0, len(parsed.Shares))
+	for _, entry := range parsed.Shares {
+		shares = append(shares, &Share{
+			Name:                   entry.Name,
+			ID:                     entry.ID,
+			ReadOnly:               entry.ReadOnly,
+			HidePasswords:          entry.HidePasswords,
+			AdminUser:              entry.AdminUser,
+			GivePermissionToOthers: entry.GivePermissionToOthers,
+		})
+	}
+
+	c.setSharesCache(shares)
+	return shares, nil
+}
+
+// ShareUsers returns the members of the shared folder identified by
+// shareID, along with each member's permissions.
+func (c *Client) ShareUsers(ctx context.Context, shareID string) ([]*ShareUser, error) {
+	if share := c.cachedShareByID(shareID); share != nil && !share.AdminUser {
+		return nil, &SharePermissionDeniedError{Share: share.Name, Action: "list users of"}
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, "/share.php", url.Values{
+		"sharejsapi": {"1"},
+		"id":         {shareID},
+		"getusers":   {"1"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doIdempotent(req)
+	if err != nil {
+		return nil, fmt.Errorf("lastpass: fetching share users: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var parsed struct {
+		Users []struct {
+			Email                  string `xml:"username,attr"`
+			ReadOnly               bool   `xml:"readonly,attr"`
+			Admin                  bool   `xml:"admin,attr"`
+			HidePasswords          bool   `xml:"give_hide_passwords,attr"`
+			GivePermissionToOthers bool   `xml:"give_permission,attr"`
+		} `xml:"user"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("lastpass: parsing share users response: %w", err)
+	}
+
+	users := make([]*ShareUser, 0, len(parsed.Users))
+	for _, u := range parsed.Users {
+		users = append(users, &ShareUser{
+			Email:                  u.Email,
+			ReadOnly:               u.ReadOnly,
+			Admin:                  u.Admin,
+			HidePasswords:          u.HidePasswords,
+			GivePermissionToOthers: u.GivePermissionToOthers,
+		})
+	}
+	return users, nil
+}
+
+// SetSharePermissions updates userEmail's permissions on the shared folder
+// identified by shareID.
+func (c *Client) SetSharePermissions(ctx context.Context, shareID, userEmail string, perms SharePermissions) error {
+	if share := c.cachedShareByID(shareID); share != nil && !share.AdminUser {
+		return &SharePermissionDeniedError{Share: share.Name, Action: "modify permissions on"}
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/share.php", url.Values{
+		"sharejsapi":    {"1"},
+		"id":            {shareID},
+		"username":      {userEmail},
+		"readonly":      {boolToFormValue(perms.ReadOnly)},
+		"give_admin":    {boolToFormValue(perms.Admin)},
+		"hidepasswords": {boolToFormValue(perms.HidePasswords)},
+		"canadminister": {boolToFormValue(perms.GivePermissionToOthers)},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := c.doMutation(req)
+	if err != nil {
+		return fmt.Errorf("lastpass: setting share permissions: %w", err)
+	}
+	return resp.Body.Close()
+}
+
+// validateShareWrite rejects, without a network round trip, a write to an
+// account whose cached shared folder permissions disallow it.
+func (c *Client) validateShareWrite(acct *Account) error {
+	if acct.Share == "" {
+		return nil
+	}
+	share := c.cachedShareByName(acct.Share)
+	if share == nil {
+		// No cached permissions: Shares() was never called, so we can't
+		// pre-validate. Let the request go to the server, which still
+		// enforces the real permission.
+		return nil
+	}
+	if share.ReadOnly {
+		return &ReadOnlyShareError{Share: share.Name}
+	}
+	if share.HidePasswords && acct.Password != "" {
+		return &HiddenPasswordShareError{Share: share.Name}
+	}
+	return nil
+}
+
+func (c *Client) setSharesCache(shares []*Share) {
+	byName := make(map[string]*Share, len(shares))
+	byID := make(map[string]*Share, len(shares))
+	for _, s := range shares {
+		byName[s.Name] = s
+		byID[s.ID] = s
+	}
+
+	c.sharesMu.Lock()
+	defer c.sharesMu.Unlock()
+	c.sharesByName = byName
+	c.sharesByID = byID
+}
+
+func (c *Client) cachedShareByName(name string) *Share {
+	c.sharesMu.RLock()
+	defer c.sharesMu.RUnlock()
+	return c.sharesByName[name]
+}
+
+func (c *Client) cachedShareByID(id string) *Share {
+	c.sharesMu.RLock()
+	defer c.sharesMu.RUnlock()
+	return c.sharesByID[id]
+}
+
+func boolToFormValue(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
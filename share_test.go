@@ -0,0 +1,45 @@
+package lastpass
+
+import "testing"
+
+func TestClient_ValidateShareWrite(t *testing.T) {
+	c := &Client{}
+	c.setSharesCache([]*Share{
+		{Name: "ReadOnlyShare", ID: "1", ReadOnly: true},
+		{Name: "WritableShare", ID: "2"},
+		{Name: "HiddenPasswordShare", ID: "3", HidePasswords: true},
+	})
+
+	if err := c.validateShareWrite(&Account{Share: "ReadOnlyShare"}); err == nil {
+		t.Fatal("expected ReadOnlyShareError, got nil")
+	} else if _, ok := err.(*ReadOnlyShareError); !ok {
+		t.Fatalf("expected *ReadOnlyShareError, got %T: %v", err, err)
+	}
+
+	if err := c.validateShareWrite(&Account{Share: "HiddenPasswordShare", Password: "secret"}); err == nil {
+		t.Fatal("expected HiddenPasswordShareError, got nil")
+	} else if _, ok := err.(*HiddenPasswordShareError); !ok {
+		t.Fatalf("expected *HiddenPasswordShareError, got %T: %v", err, err)
+	}
+
+	// Writing to a hidden-password share is fine as long as no password is
+	// actually being sent, e.g. updating only the URL or notes.
+	if err := c.validateShareWrite(&Account{Share: "HiddenPasswordShare"}); err != nil {
+		t.Fatalf("hidden-password share without a password should not be rejected, got: %v", err)
+	}
+
+	if err := c.validateShareWrite(&Account{Share: "WritableShare"}); err != nil {
+		t.Fatalf("writable share should not be rejected, got: %v", err)
+	}
+
+	// An account with no cached share info is let through client-side; the
+	// server is the source of truth until Shares() has been called.
+	if err := c.validateShareWrite(&Account{Share: "UnknownShare"}); err != nil {
+		t.Fatalf("uncached share should not be rejected client-side, got: %v", err)
+	}
+
+	// Private accounts are never gated by share permissions.
+	if err := c.validateShareWrite(&Account{}); err != nil {
+		t.Fatalf("private account should not be rejected, got: %v", err)
+	}
+}
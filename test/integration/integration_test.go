@@ -3,6 +3,7 @@ package integration_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -75,16 +76,18 @@ var _ = Describe("Integration", func() {
 			acct = &Account{ID: id}
 		})
 		Describe("Update()", func() {
-			It("returns AccountNotFoundError", func() {
-				Expect(client.Update(context.Background(), acct)).To(
-					MatchError(&AccountNotFoundError{ID: id}))
+			It("returns an error matching ErrAccountNotFound", func() {
+				err := client.Update(context.Background(), acct)
+				Expect(err).To(MatchError(&AccountNotFoundError{ID: id}))
+				Expect(errors.Is(err, ErrAccountNotFound)).To(BeTrue())
 			})
 		})
 
 		Describe("Delete()", func() {
-			It("returns AccountNotFoundError", func() {
-				Expect(client.Delete(context.Background(), acct)).To(
-					MatchError(&AccountNotFoundError{ID: id}))
+			It("returns an error matching ErrAccountNotFound", func() {
+				err := client.Delete(context.Background(), acct)
+				Expect(err).To(MatchError(&AccountNotFoundError{ID: id}))
+				Expect(errors.Is(err, ErrAccountNotFound)).To(BeTrue())
 			})
 		})
 	})
@@ -148,9 +151,9 @@ var _ = Describe("Integration", func() {
 				Name:  "fake-name",
 				Share: shareReadOnly,
 			}
-			Expect(client.Add(context.Background(), acct)).To(
-				MatchError(fmt.Sprintf(
-					"Account cannot be written to read-only shared folder %s.", shareReadOnly)))
+			err := client.Add(context.Background(), acct)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, ErrReadOnlyShare)).To(BeTrue())
 		})
 	})
 
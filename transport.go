@@ -0,0 +1,185 @@
+package lastpass
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures the retrying behavior installed by
+// WithRetryPolicy. The zero value is not usable directly; start from
+// DefaultRetryPolicy and override fields as needed.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, including the first.
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry; it doubles on each
+	// subsequent attempt, capped at MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Jitter is the fraction (0-1) of random variance added to each delay,
+	// to avoid many clients retrying in lockstep.
+	Jitter float64
+
+	// OnRetry, if set, is called before each retry's backoff sleep.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// DefaultRetryPolicy is a conservative starting point: 4 attempts, backing
+// off from 200ms up to 5s.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// doIdempotent performs req, retrying per the Client's RetryPolicy (if
+// any) on 5xx responses and network errors.
+func (c *Client) doIdempotent(req *http.Request) (*http.Response, error) {
+	return c.doWithRetry(req, true)
+}
+
+// doMutation performs req, retrying per the Client's RetryPolicy (if any)
+// only when the failure happened while establishing the connection, i.e.
+// before anything could have reached the server.
+func (c *Client) doMutation(req *http.Request) (*http.Response, error) {
+	return c.doWithRetry(req, false)
+}
+
+func (c *Client) doWithRetry(req *http.Request, idempotent bool) (*http.Response, error) {
+	if c.retryPolicy == nil {
+		return c.httpClient.Do(req)
+	}
+	policy := c.retryPolicy
+
+	var lastErr error
+	for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := rewindBody(req); err != nil {
+				return nil, fmt.Errorf("lastpass: rebuilding request for retry: %w", err)
+			}
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			return resp, nil
+		}
+
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		var retryAfter time.Duration
+		switch {
+		case err != nil:
+			if isContextErr(err) {
+				return nil, err
+			}
+			if !idempotent && !isConnectionEstablishmentError(err) {
+				return nil, err
+			}
+			lastErr = err
+		default:
+			if !idempotent {
+				// The request reached the server and it responded; a retry
+				// here risks creating/deleting the account twice.
+				return resp, nil
+			}
+			lastErr = fmt.Errorf("lastpass: server returned %s", resp.Status)
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			resp.Body.Close()
+		}
+
+		if attempt == policy.MaxAttempts-1 {
+			break
+		}
+
+		delay := retryAfter
+		if delay == 0 {
+			delay = backoffDelay(*policy, attempt)
+		}
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt+1, lastErr, delay)
+		}
+		if !sleepOrDone(req.Context(), delay) {
+			return nil, req.Context().Err()
+		}
+	}
+	return nil, lastErr
+}
+
+// rewindBody resets req.Body to a fresh reader via req.GetBody, which
+// http.NewRequest populates automatically for the in-memory body types
+// this package uses (strings.Reader/bytes.Reader/bytes.Buffer).
+func rewindBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
+
+// isContextErr reports whether err is, or wraps, context.Canceled or
+// context.DeadlineExceeded. Go's HTTP stack wraps these in *url.Error, so
+// this must use errors.Is rather than ==.
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// isConnectionEstablishmentError reports whether err happened while
+// dialing, i.e. before any bytes of the request could have reached the
+// server, which makes it safe to retry even a non-idempotent mutation.
+func isConnectionEstablishmentError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func backoffDelay(policy RetryPolicy, attempt int) time.Duration {
+	delay := policy.BaseDelay << attempt
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	if policy.Jitter > 0 {
+		jitter := time.Duration(float64(delay) * policy.Jitter * rand.Float64())
+		delay += jitter
+	}
+	return delay
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds. It returns
+// 0 (meaning "use the policy's backoff instead") for empty or malformed
+// values; the HTTP-date form is rare enough from LastPass's endpoints that
+// it is not worth the added complexity here.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// sleepOrDone waits for delay, returning false early if ctx is done first.
+func sleepOrDone(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,123 @@
+package lastpass
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoIdempotent_RetriesUntilSuccessAfter5xx(t *testing.T) {
+	var failuresLeft int32 = 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&failuresLeft, -1) >= 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	var retries int
+	client := &Client{
+		httpClient: http.DefaultClient,
+		retryPolicy: &RetryPolicy{
+			MaxAttempts: 5,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    5 * time.Millisecond,
+			OnRetry:     func(attempt int, err error, delay time.Duration) { retries++ },
+		},
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequestWithContext: %v", err)
+	}
+
+	resp, err := client.doIdempotent(req)
+	if err != nil {
+		t.Fatalf("doIdempotent: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("final status = %d, want 200", resp.StatusCode)
+	}
+	if retries != 2 {
+		t.Fatalf("retries = %d, want 2", retries)
+	}
+}
+
+func TestDoIdempotent_CancelledContextReturnsQuickly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := &Client{
+		httpClient: http.DefaultClient,
+		retryPolicy: &RetryPolicy{
+			MaxAttempts: 10,
+			BaseDelay:   5 * time.Second,
+			MaxDelay:    5 * time.Second,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequestWithContext: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		client.doIdempotent(req)
+		close(done)
+	}()
+
+	// Let the first (failing) attempt happen, then cancel: with a 5s
+	// backoff configured, only a context-aware retry loop returns fast.
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("doIdempotent did not return promptly after context cancellation")
+	}
+}
+
+func TestDoMutation_DoesNotRetryAfterServerResponded(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := &Client{
+		httpClient: http.DefaultClient,
+		retryPolicy: &RetryPolicy{
+			MaxAttempts: 5,
+			BaseDelay:   time.Millisecond,
+		},
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequestWithContext: %v", err)
+	}
+
+	resp, err := client.doMutation(req)
+	if err != nil {
+		t.Fatalf("doMutation: %v", err)
+	}
+	resp.Body.Close()
+
+	if hits != 1 {
+		t.Fatalf("server was hit %d times, want 1 (non-idempotent writes must not retry after a response)", hits)
+	}
+}